@@ -0,0 +1,240 @@
+package simfaas
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrQueueFull is returned by submit when the async queue has no room for
+// another invocation. It reflects server-side backpressure rather than a
+// malformed request, so callers should surface it as a 503, not a 400.
+var ErrQueueFull = errors.New("async queue is full")
+
+// JobStatus is the lifecycle state of an asynchronously executed
+// invocation.
+type JobStatus string
+
+const (
+	JobStatusQueued  JobStatus = "queued"
+	JobStatusRunning JobStatus = "running"
+	JobStatusDone    JobStatus = "done"
+	JobStatusFailed  JobStatus = "failed"
+)
+
+const (
+	asyncHeader    = "X-Async"
+	callbackHeader = "X-Callback-Url"
+
+	defaultAsyncWorkers    = 10
+	defaultAsyncQueueDepth = 100
+)
+
+// Job is the state of an asynchronously executed invocation, as returned
+// by GET /v2/jobs/{id}.
+type Job struct {
+	ID     string           `json:"id"`
+	Status JobStatus        `json:"status"`
+	Report *ExecutionReport `json:"report,omitempty"`
+	Error  string           `json:"error,omitempty"`
+}
+
+// asyncInvocation is the unit of work handed to the async queue's
+// workers.
+type asyncInvocation struct {
+	fnName      string
+	runtime     *time.Duration
+	callbackURL string
+	enqueuedAt  time.Time
+	job         *Job
+}
+
+// asyncQueue is a bounded worker pool that executes invocations
+// asynchronously on behalf of a gateway, enforcing a queue depth and a
+// per-function concurrency cap so a single function cannot starve the
+// rest of the queue. Invocations a worker can't run yet because their
+// function is at its concurrency limit go through retryQueue rather than
+// back through queue, so a function being retried repeatedly can't eat
+// into the submit-time queue depth and cause unrelated functions'
+// submissions to be rejected as ErrQueueFull.
+type asyncQueue struct {
+	g          *gateway
+	queue      chan *asyncInvocation
+	retryQueue chan *asyncInvocation
+	fnLimit    int
+
+	mu       sync.Mutex
+	jobs     map[string]*Job
+	inFlight map[string]int
+	nextID   uint64
+}
+
+// newAsyncQueue starts workers goroutines consuming from a queue of the
+// given depth, each invocation respecting fnLimit concurrent executions
+// per function (0 means unbounded).
+func newAsyncQueue(g *gateway, workers, queueDepth, fnLimit int) *asyncQueue {
+	if workers <= 0 {
+		workers = defaultAsyncWorkers
+	}
+	if queueDepth <= 0 {
+		queueDepth = defaultAsyncQueueDepth
+	}
+	q := &asyncQueue{
+		g:          g,
+		queue:      make(chan *asyncInvocation, queueDepth),
+		retryQueue: make(chan *asyncInvocation, queueDepth),
+		fnLimit:    fnLimit,
+		jobs:       map[string]*Job{},
+		inFlight:   map[string]int{},
+	}
+	for i := 0; i < workers; i++ {
+		go q.work()
+	}
+	return q
+}
+
+// submit enqueues fnName for asynchronous execution and returns the Job
+// tracking its progress. It fails if the queue is currently full.
+func (q *asyncQueue) submit(fnName string, runtime *time.Duration, callbackURL string) (*Job, error) {
+	id := fmt.Sprintf("job-%d", atomic.AddUint64(&q.nextID, 1))
+	job := &Job{ID: id, Status: JobStatusQueued}
+
+	q.mu.Lock()
+	q.jobs[id] = job
+	q.mu.Unlock()
+
+	inv := &asyncInvocation{fnName: fnName, runtime: runtime, callbackURL: callbackURL, enqueuedAt: time.Now(), job: job}
+	select {
+	case q.queue <- inv:
+		return job, nil
+	default:
+		q.mu.Lock()
+		job.Status = JobStatusFailed
+		job.Error = ErrQueueFull.Error()
+		q.mu.Unlock()
+		return job, ErrQueueFull
+	}
+}
+
+// get returns the Job registered under id, if any.
+func (q *asyncQueue) get(id string) (*Job, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job, ok := q.jobs[id]
+	return job, ok
+}
+
+func (q *asyncQueue) work() {
+	for {
+		// retryQueue is drained preferentially so invocations waiting on
+		// a freed-up slot don't keep losing the race to fresh
+		// submissions on queue.
+		var inv *asyncInvocation
+		select {
+		case inv = <-q.retryQueue:
+		default:
+			select {
+			case inv = <-q.retryQueue:
+			case inv = <-q.queue:
+			}
+		}
+
+		if !q.acquire(inv.fnName) {
+			// inv.fnName is already at its concurrency limit. Hand the
+			// invocation to retryQueue instead of blocking this worker on
+			// a sleep loop, so it stays free to drain independent jobs
+			// for other functions behind it.
+			go q.requeue(inv)
+			continue
+		}
+		q.run(inv)
+		q.release(inv.fnName)
+	}
+}
+
+// acquire reserves a per-function concurrency slot for fnName without
+// blocking, reporting whether one was available. It always succeeds when
+// no per-function limit is configured.
+func (q *asyncQueue) acquire(fnName string) bool {
+	if q.fnLimit <= 0 {
+		return true
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.inFlight[fnName] >= q.fnLimit {
+		return false
+	}
+	q.inFlight[fnName]++
+	return true
+}
+
+// release frees a per-function concurrency slot reserved by acquire.
+func (q *asyncQueue) release(fnName string) {
+	if q.fnLimit <= 0 {
+		return
+	}
+	q.mu.Lock()
+	q.inFlight[fnName]--
+	q.mu.Unlock()
+}
+
+// requeue waits briefly and resubmits inv to retryQueue, used when its
+// function was at its concurrency limit. It runs on its own goroutine so
+// the worker that couldn't run inv is never blocked by it, and it
+// targets retryQueue rather than queue so a function stuck retrying
+// doesn't consume the bounded capacity new submissions rely on.
+func (q *asyncQueue) requeue(inv *asyncInvocation) {
+	time.Sleep(10 * time.Millisecond)
+	q.retryQueue <- inv
+}
+
+func (q *asyncQueue) run(inv *asyncInvocation) {
+
+	q.g.metrics().ObserveQueueWait(inv.fnName, time.Since(inv.enqueuedAt))
+
+	q.mu.Lock()
+	inv.job.Status = JobStatusRunning
+	q.mu.Unlock()
+
+	report, err := q.g.Platform.Run(inv.fnName, inv.runtime)
+
+	q.mu.Lock()
+	if err != nil {
+		inv.job.Status = JobStatusFailed
+		inv.job.Error = err.Error()
+	} else {
+		inv.job.Status = JobStatusDone
+		inv.job.Report = report
+	}
+	q.mu.Unlock()
+
+	if inv.callbackURL != "" {
+		q.deliverCallback(inv)
+	}
+}
+
+func (q *asyncQueue) deliverCallback(inv *asyncInvocation) {
+	body, err := json.Marshal(inv.job)
+	if err != nil {
+		log.Printf("%s: failed to marshal callback report: %v", inv.job.ID, err)
+		return
+	}
+	resp, err := http.Post(inv.callbackURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Printf("%s: failed to deliver callback to %s: %v", inv.job.ID, inv.callbackURL, err)
+		return
+	}
+	resp.Body.Close()
+}
+
+// isAsyncRequest reports whether the request asked for asynchronous
+// execution via the X-Async header.
+func isAsyncRequest(h *http.Header) bool {
+	return h.Get(asyncHeader) == "true"
+}
@@ -0,0 +1,127 @@
+package simfaas
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestQueue(queueDepth, fnLimit int) *asyncQueue {
+	return &asyncQueue{
+		queue:      make(chan *asyncInvocation, queueDepth),
+		retryQueue: make(chan *asyncInvocation, queueDepth),
+		fnLimit:    fnLimit,
+		jobs:       map[string]*Job{},
+		inFlight:   map[string]int{},
+	}
+}
+
+func TestAsyncQueueSubmitQueueFull(t *testing.T) {
+	q := newTestQueue(1, 0)
+
+	if _, err := q.submit("fn", nil, ""); err != nil {
+		t.Fatalf("first submit: unexpected error: %v", err)
+	}
+
+	job, err := q.submit("fn", nil, "")
+	if !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("second submit: err = %v, want ErrQueueFull", err)
+	}
+	if job.Status != JobStatusFailed {
+		t.Errorf("job.Status = %q, want %q", job.Status, JobStatusFailed)
+	}
+	if job.Error != ErrQueueFull.Error() {
+		t.Errorf("job.Error = %q, want %q", job.Error, ErrQueueFull.Error())
+	}
+}
+
+func TestAsyncQueueSubmitTracksJob(t *testing.T) {
+	q := newTestQueue(1, 0)
+
+	submitted, err := q.submit("fn", nil, "")
+	if err != nil {
+		t.Fatalf("submit: unexpected error: %v", err)
+	}
+	got, ok := q.get(submitted.ID)
+	if !ok {
+		t.Fatalf("get(%q): not found", submitted.ID)
+	}
+	if got != submitted {
+		t.Errorf("get(%q) = %+v, want %+v", submitted.ID, got, submitted)
+	}
+	if got.Status != JobStatusQueued {
+		t.Errorf("job.Status = %q, want %q", got.Status, JobStatusQueued)
+	}
+}
+
+func TestAsyncQueueAcquireRelease(t *testing.T) {
+	q := newTestQueue(10, 2)
+
+	if !q.acquire("fn") {
+		t.Fatal("acquire 1: want true")
+	}
+	if !q.acquire("fn") {
+		t.Fatal("acquire 2: want true")
+	}
+	if q.acquire("fn") {
+		t.Fatal("acquire 3: want false, fnLimit is 2")
+	}
+
+	// another function is unaffected by fn's limit.
+	if !q.acquire("other") {
+		t.Fatal("acquire for other fn: want true")
+	}
+
+	q.release("fn")
+	if !q.acquire("fn") {
+		t.Fatal("acquire after release: want true")
+	}
+}
+
+func TestAsyncQueueAcquireUnbounded(t *testing.T) {
+	q := newTestQueue(10, 0)
+	for i := 0; i < 100; i++ {
+		if !q.acquire("fn") {
+			t.Fatalf("acquire %d: want true with no fnLimit configured", i)
+		}
+	}
+}
+
+// TestAsyncQueueRequeue confirms that requeue resubmits the invocation it
+// was given to retryQueue.
+func TestAsyncQueueRequeue(t *testing.T) {
+	q := newTestQueue(10, 0)
+	inv := &asyncInvocation{fnName: "busy", job: &Job{ID: "job-1"}, enqueuedAt: time.Now()}
+
+	go q.requeue(inv)
+
+	select {
+	case got := <-q.retryQueue:
+		if got != inv {
+			t.Errorf("requeued invocation = %+v, want %+v", got, inv)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for requeued invocation")
+	}
+}
+
+// TestAsyncQueueRequeueDoesNotOccupyQueue confirms that a requeued
+// invocation never lands on queue, the channel submit draws capacity from,
+// so a function being retried repeatedly can't cause unrelated submissions
+// to be rejected as ErrQueueFull.
+func TestAsyncQueueRequeueDoesNotOccupyQueue(t *testing.T) {
+	q := newTestQueue(1, 0)
+	inv := &asyncInvocation{fnName: "busy", job: &Job{ID: "job-1"}, enqueuedAt: time.Now()}
+
+	q.requeue(inv)
+
+	select {
+	case <-q.queue:
+		t.Fatal("requeue placed the invocation on queue, want retryQueue")
+	default:
+	}
+
+	if _, err := q.submit("other", nil, ""); err != nil {
+		t.Fatalf("submit for unrelated function: unexpected error: %v", err)
+	}
+}
@@ -1,14 +1,69 @@
 package simfaas
 
 import (
+	"context"
 	"errors"
+	"io"
+	"net/http"
+	"net/url"
 )
 
 const (
 	CUSTOM_FN_HEADER = "X-CustomFn"
 )
 
-type CustomFn = func(b []byte) ([]byte, error)
+// CustomRequest carries the information a CustomFn needs to synthesize a
+// response for a simulated function invocation.
+type CustomRequest struct {
+	Name   string
+	Method string
+	Header http.Header
+	Query  url.Values
+	Body   []byte
+}
+
+// CustomResponse describes the HTTP response a CustomFn wants written
+// back for an invocation. Body is used for small, fully-buffered
+// responses; set BodyReader instead to stream the response.
+type CustomResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	BodyReader io.Reader
+}
+
+// CustomError lets a CustomFn control the HTTP status code
+// HandleFunctionRun writes, instead of every error collapsing to 400.
+type CustomError struct {
+	StatusCode int
+	Err        error
+}
+
+func (e *CustomError) Error() string { return e.Err.Error() }
+func (e *CustomError) Unwrap() error { return e.Err }
+
+// CustomFn synthesizes a response for a simulated function invocation.
+// ctx carries the incoming request's context, including the deadline
+// imposed by the simulated function's runtime, so a CustomFn observes
+// cancellation the same way a real function would when it times out.
+type CustomFn = func(ctx context.Context, req *CustomRequest) (*CustomResponse, error)
+
+// LegacyCustomFn is the pre-context CustomFn signature. Wrap it with
+// WrapLegacy to register it as a CustomFn.
+type LegacyCustomFn = func(b []byte) ([]byte, error)
+
+// WrapLegacy adapts a LegacyCustomFn to the current CustomFn signature
+// for backwards compatibility. ctx is ignored, and the legacy function's
+// output is returned as a 200 response body.
+func WrapLegacy(fn LegacyCustomFn) CustomFn {
+	return func(_ context.Context, req *CustomRequest) (*CustomResponse, error) {
+		b, err := fn(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		return &CustomResponse{StatusCode: http.StatusOK, Body: b}, nil
+	}
+}
 
 type CustomHandler struct {
 	// a parser function to extract a key used look up a custom handler in
@@ -17,8 +72,8 @@ type CustomHandler struct {
 	Handlers map[string]CustomFn
 }
 
-func (c CustomHandler) ExecFn(fnName string, b []byte) ([]byte, error) {
-	customHandler, err := c.GetFn(fnName)
+func (c CustomHandler) ExecFn(ctx context.Context, req *CustomRequest) (*CustomResponse, error) {
+	customHandler, err := c.GetFn(req.Name)
 	if err != nil {
 		return nil, err
 	}
@@ -28,6 +83,6 @@ func (c CustomHandler) ExecFn(fnName string, b []byte) ([]byte, error) {
 		return nil, errors.New("Parsed handler does not exist")
 	}
 
-	// execute and return report
-	return fn(b)
+	// execute and return response
+	return fn(ctx, req)
 }
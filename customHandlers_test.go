@@ -0,0 +1,82 @@
+package simfaas
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestCustomErrorUnwrap(t *testing.T) {
+	wrapped := errors.New("boom")
+	ce := &CustomError{StatusCode: http.StatusTeapot, Err: wrapped}
+
+	if ce.Error() != wrapped.Error() {
+		t.Errorf("Error() = %q, want %q", ce.Error(), wrapped.Error())
+	}
+	if !errors.Is(ce, wrapped) {
+		t.Error("errors.Is(ce, wrapped) = false, want true via Unwrap")
+	}
+}
+
+func TestWrapLegacy(t *testing.T) {
+	legacy := func(b []byte) ([]byte, error) {
+		return append([]byte("echo:"), b...), nil
+	}
+	fn := WrapLegacy(legacy)
+
+	res, err := fn(context.Background(), &CustomRequest{Body: []byte("hi")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("StatusCode = %d, want %d", res.StatusCode, http.StatusOK)
+	}
+	if string(res.Body) != "echo:hi" {
+		t.Errorf("Body = %q, want %q", res.Body, "echo:hi")
+	}
+}
+
+func TestWrapLegacyError(t *testing.T) {
+	wantErr := errors.New("legacy failure")
+	legacy := func(b []byte) ([]byte, error) { return nil, wantErr }
+	fn := WrapLegacy(legacy)
+
+	res, err := fn(context.Background(), &CustomRequest{})
+	if !errors.Is(err, wantErr) {
+		t.Errorf("err = %v, want %v", err, wantErr)
+	}
+	if res != nil {
+		t.Errorf("res = %+v, want nil", res)
+	}
+}
+
+func TestCustomHandlerExecFn(t *testing.T) {
+	h := CustomHandler{
+		GetFn: func(name string) (string, error) { return name, nil },
+		Handlers: map[string]CustomFn{
+			"greet": func(ctx context.Context, req *CustomRequest) (*CustomResponse, error) {
+				return &CustomResponse{StatusCode: http.StatusOK, Body: []byte("hello " + req.Name)}, nil
+			},
+		},
+	}
+
+	res, err := h.ExecFn(context.Background(), &CustomRequest{Name: "greet"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(res.Body) != "hello greet" {
+		t.Errorf("Body = %q, want %q", res.Body, "hello greet")
+	}
+}
+
+func TestCustomHandlerExecFnUnknownHandler(t *testing.T) {
+	h := CustomHandler{
+		GetFn:    func(name string) (string, error) { return "missing", nil },
+		Handlers: map[string]CustomFn{},
+	}
+
+	if _, err := h.ExecFn(context.Background(), &CustomRequest{Name: "anything"}); err == nil {
+		t.Fatal("ExecFn with no matching handler: want error, got nil")
+	}
+}
@@ -8,7 +8,6 @@ import (
 	"net/http"
 	"net/url"
 	"regexp"
-	"strconv"
 	"strings"
 	"time"
 )
@@ -16,34 +15,14 @@ import (
 // Fission is a wrapper on top of simfaas that emulates a part of the
 // interface of Fission.
 type Fission struct {
-	Platform  *Platform
-	FnFactory func(name string) *FunctionConfig
-
-	// CreateUndefinedFunctions enables, if set to true,
-	// the automatic creation of a function if it is called.
-	CreateUndefinedFunctions bool
-	// CustomFn allows us to execute custom functions based on the functio name passed to the system
-	CustomFn CustomHandler
-}
-
-func (f *Fission) Start() error {
-	return f.Platform.Start()
-}
-
-func (f *Fission) Close() error {
-	return f.Platform.Close()
+	gateway
 }
 
 // GetServiceForFunction emulates the mapping of a function to a service
 // name/host. Currently it just returns the name of the function as the
 // service name.
 func (f *Fission) GetServiceForFunction(fnName string) (string, error) {
-	f.createIfUndefined(fnName)
-	fn, ok := f.Platform.Get(fnName)
-	if !ok {
-		return "", ErrFunctionNotFound
-	}
-	return fn.name, nil
+	return f.serviceForFunction(fnName)
 }
 
 // TapService deploys (or keeps deployed) a function instance for the function.
@@ -54,57 +33,36 @@ func (f *Fission) TapService(svcURL string) error {
 	if len(svcURL) == 0 {
 		return errors.New("no url provided to tap")
 	}
-	fnName := svc2fn(svcURL)
-	f.createIfUndefined(fnName)
-
-	fn, ok := f.Platform.Get(fnName)
-	if !ok {
-		return ErrFunctionNotFound
-	}
-
-	// Tapping is an async operation
-	go func() {
-		_ = f.Platform.deploy(fn)
-	}()
-	return nil
+	return f.tap(svc2fn(svcURL))
 }
 
 // Run emulates the execution of a Fission Function.
 //
 // If the runtime is not nil it will be used to override the runtime
-// specified in the config of the function.
+// specified in the config of the function. If a CustomFn was invoked, its
+// response body is folded into the ExecutionReport's Response field.
 func (f *Fission) Run(r *http.Request, runtime *time.Duration) (*ExecutionReport, error) {
 	fnName := getFunctionNameFromUrl(r.URL)
-	f.createIfUndefined(fnName)
-	report, err := f.Platform.Run(fnName, runtime)
+	report, custom, err := f.run(r, fnName, runtime)
 	if err != nil {
 		return nil, err
 	}
-
-	// before simulating execution, execute our custom response generator.
-	// The time taken for this is considered negligable and not included in
-	// the simulation stats
-	if useCustomFn(&r.Header) {
-		body, err := ioutil.ReadAll(r.Body)
-		if err != nil {
-			return nil, err
-		}
-		res, err := f.CustomFn.ExecFn(fnName, body)
-		if err != nil {
-			return nil, err
-		}
-		report.Response = string(res)
+	if custom != nil {
+		report.Response = string(custom.Body)
 	}
-
 	return report, nil
 }
 
 func (f *Fission) Serve() http.Handler {
+	m := f.metrics()
 	handler := &RegexpHandler{}
-	handler.HandleFunc(regexp.MustCompile("/v2/functions/.*"), f.HandleFunctionsGet)
-	handler.HandleFunc(regexp.MustCompile("/v2/tapService"), f.HandleTapService)
-	handler.HandleFunc(regexp.MustCompile("/v2/getServiceForFunction"), f.HandleGetServiceForFunction)
-	handler.HandleFunc(regexp.MustCompile("/fission-function/.*"), f.HandleFunctionRun)
+	handler.Use(RecoverMiddleware, LoggingMiddleware, GzipMiddleware)
+	handler.Get(regexp.MustCompile("/v2/functions/.*"), m.Instrument("functionsGet", http.HandlerFunc(f.HandleFunctionsGet)))
+	handler.Post(regexp.MustCompile("/v2/tapService"), m.Instrument("tapService", http.HandlerFunc(f.HandleTapService)))
+	handler.Post(regexp.MustCompile("/v2/getServiceForFunction"), m.Instrument("getServiceForFunction", http.HandlerFunc(f.HandleGetServiceForFunction)))
+	handler.Post(regexp.MustCompile("/fission-function/.*"), m.Instrument("functionRun", http.HandlerFunc(f.HandleFunctionRun)))
+	handler.Get(regexp.MustCompile("/v2/jobs/.*"), m.Instrument("jobStatus", http.HandlerFunc(f.handleJobStatus)))
+	handler.Get(regexp.MustCompile("/metrics"), m.Handler())
 	return handler
 }
 
@@ -165,21 +123,32 @@ func (f *Fission) HandleFunctionsGet(w http.ResponseWriter, r *http.Request) {
 // It checks for the presence of the runtime query parameter,
 // which allows you to override the runtime of the function.
 func (f *Fission) HandleFunctionRun(w http.ResponseWriter, r *http.Request) {
-	// Parse arguments: fnname, runtime
-	var seconds float64
-	var err error
-	if queryRuntime := r.URL.Query().Get("runtime"); len(queryRuntime) > 0 {
-		seconds, err = strconv.ParseFloat(queryRuntime, 64)
+	runtime, err := runtimeOverride(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if isAsyncRequest(&r.Header) {
+		fnName := getFunctionNameFromUrl(r.URL)
+		job, err := f.runAsync(r, fnName, runtime)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			http.Error(w, err.Error(), asyncErrorStatus(err, http.StatusBadRequest))
 			return
 		}
+		w.Header().Set("Location", "/v2/jobs/"+job.ID)
+		w.WriteHeader(http.StatusAccepted)
+		return
 	}
-	runtime := time.Duration(seconds * float64(time.Second))
 
-	report, err := f.Run(r, &runtime)
+	fnName := getFunctionNameFromUrl(r.URL)
+	report, custom, err := f.run(r, fnName, runtime)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		http.Error(w, err.Error(), customErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+	if custom != nil {
+		writeCustomResponse(w, custom)
 		return
 	}
 
@@ -192,17 +161,6 @@ func (f *Fission) HandleFunctionRun(w http.ResponseWriter, r *http.Request) {
 	w.Write(result)
 }
 
-func (f *Fission) createIfUndefined(fnName string) {
-	// Create function in simulator if undefined
-	if f.CreateUndefinedFunctions {
-		if _, ok := f.Platform.Get(fnName); !ok {
-			fnCfg := f.FnFactory(fnName)
-			f.Platform.Define(fnName, fnCfg)
-			log.Printf("Created new function %s with config: %+v", fnName, fnCfg)
-		}
-	}
-}
-
 type ObjectMeta struct {
 	Name string `json:"name,omitempty" protobuf:"bytes,1,opt,name=name"`
 	// Namespace string `json:"namespace,omitempty" protobuf:"bytes,3,opt,name=namespace"`
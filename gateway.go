@@ -0,0 +1,263 @@
+package simfaas
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Gradecak/simfaas/metrics"
+)
+
+// gateway holds the state and behaviour shared by every emulated FaaS
+// front-end: the simulated Platform backing invocations, the factory used
+// to define functions on first use, and the optional custom-response and
+// metrics hooks. Fission, OpenFaaS and Knative each embed a gateway and
+// add only the parts of their HTTP contract that differ.
+type gateway struct {
+	Platform  *Platform
+	FnFactory func(name string) *FunctionConfig
+
+	// CreateUndefinedFunctions enables, if set to true,
+	// the automatic creation of a function if it is called.
+	CreateUndefinedFunctions bool
+	// CustomFn allows us to execute custom functions based on the functio name passed to the system
+	CustomFn CustomHandler
+
+	// Metrics receives observations from the deploy/run paths below and
+	// from the routes each gateway registers in Serve. It defaults to a
+	// no-op collector, so instrumentation is entirely optional.
+	Metrics metrics.Collector
+
+	// AsyncWorkers is the number of goroutines processing the async
+	// invocation queue. Defaults to 10 if unset.
+	AsyncWorkers int
+	// AsyncQueueDepth bounds how many async invocations may be queued
+	// waiting for a worker before submit starts rejecting them.
+	// Defaults to 100 if unset.
+	AsyncQueueDepth int
+	// AsyncPerFunctionLimit caps how many async invocations of the same
+	// function may run concurrently. 0 means unbounded.
+	AsyncPerFunctionLimit int
+
+	asyncOnce sync.Once
+	async     *asyncQueue
+}
+
+func (g *gateway) metrics() metrics.Collector {
+	if g.Metrics == nil {
+		return metrics.Noop
+	}
+	return g.Metrics
+}
+
+func (g *gateway) Start() error {
+	return g.Platform.Start()
+}
+
+func (g *gateway) Close() error {
+	return g.Platform.Close()
+}
+
+func (g *gateway) createIfUndefined(fnName string) {
+	// Create function in simulator if undefined
+	if g.CreateUndefinedFunctions {
+		if _, ok := g.Platform.Get(fnName); !ok {
+			fnCfg := g.FnFactory(fnName)
+			g.Platform.Define(fnName, fnCfg)
+			log.Printf("Created new function %s with config: %+v", fnName, fnCfg)
+		}
+	}
+}
+
+// serviceForFunction emulates the mapping of a function to a service
+// name/host. Currently it just returns the name of the function as the
+// service name.
+func (g *gateway) serviceForFunction(fnName string) (string, error) {
+	g.createIfUndefined(fnName)
+	fn, ok := g.Platform.Get(fnName)
+	if !ok {
+		return "", ErrFunctionNotFound
+	}
+	return fn.name, nil
+}
+
+// tap deploys (or keeps deployed) a function instance for fnName, the way
+// a Fission tapService/OpenFaaS prewarm call would.
+func (g *gateway) tap(fnName string) error {
+	g.createIfUndefined(fnName)
+
+	fn, ok := g.Platform.Get(fnName)
+	if !ok {
+		return ErrFunctionNotFound
+	}
+
+	// Tapping is an async operation. deploy is the only instance-creation
+	// path reachable from the gateway, so every successful call is
+	// counted as a cold start; WarmHit and the instance gauges need
+	// Platform itself to report warm/idle state, which isn't exposed
+	// here yet.
+	go func() {
+		start := time.Now()
+		if err := g.Platform.deploy(fn); err != nil {
+			g.metrics().DeployFailure(fnName)
+			return
+		}
+		g.metrics().ColdStart(fnName, time.Since(start))
+	}()
+	return nil
+}
+
+// run executes fnName against g.Platform, optionally overriding the
+// runtime, and layers on the custom-handler behaviour shared by every
+// gateway's invocation route. If a CustomFn is invoked, its CustomResponse
+// is returned alongside the ExecutionReport and takes precedence over it
+// when writing the HTTP response.
+//
+// If the runtime is not nil it will be used to override the runtime
+// specified in the config of the function.
+func (g *gateway) run(r *http.Request, fnName string, runtime *time.Duration) (*ExecutionReport, *CustomResponse, error) {
+	g.createIfUndefined(fnName)
+	g.metrics().Invocation(fnName)
+	start := time.Now()
+	report, err := g.Platform.Run(fnName, runtime)
+	if err != nil {
+		if errors.Is(err, ErrFunctionNotFound) {
+			g.metrics().FunctionNotFound(fnName)
+		}
+		return nil, nil, err
+	}
+	g.metrics().ObserveRuntime(fnName, time.Since(start))
+
+	// once the simulated execution has run, execute our custom response generator.
+	// The time taken for this is considered negligable and not included in
+	// the simulation stats
+	if useCustomFn(&r.Header) {
+		deadline := runtime
+		if deadline == nil {
+			deadline = &report.Runtime
+		}
+		ctx, cancel := customFnContext(r, *deadline)
+		defer cancel()
+
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return report, nil, err
+		}
+		res, err := g.CustomFn.ExecFn(ctx, &CustomRequest{
+			Name:   fnName,
+			Method: r.Method,
+			Header: r.Header,
+			Query:  r.URL.Query(),
+			Body:   body,
+		})
+		if err != nil {
+			return report, nil, err
+		}
+		return report, res, nil
+	}
+
+	return report, nil, nil
+}
+
+// customFnContext derives the context a CustomFn should observe: r's
+// context with a deadline matching runtime, the duration the invocation
+// actually ran with, so the handler can be cancelled the same way a real
+// function would time out. runtime is zero both when the caller
+// explicitly asked for an instant function and when no override was
+// requested at all (see runtimeOverride's default), so a zero duration
+// is treated as "no deadline" rather than handing the handler an
+// already-cancelled context via context.WithTimeout(ctx, 0).
+func customFnContext(r *http.Request, runtime time.Duration) (context.Context, context.CancelFunc) {
+	if runtime <= 0 {
+		return r.Context(), func() {}
+	}
+	return context.WithTimeout(r.Context(), runtime)
+}
+
+// writeCustomResponse writes a CustomResponse returned by run as the HTTP
+// response, in place of the usual marshalled ExecutionReport.
+func writeCustomResponse(w http.ResponseWriter, res *CustomResponse) {
+	for k, vs := range res.Header {
+		for _, v := range vs {
+			w.Header().Add(k, v)
+		}
+	}
+	status := res.StatusCode
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	if res.BodyReader != nil {
+		io.Copy(w, res.BodyReader)
+		return
+	}
+	w.Write(res.Body)
+}
+
+// customErrorStatus returns the HTTP status code a CustomError wants
+// written, or fallback if err is not a *CustomError.
+func customErrorStatus(err error, fallback int) int {
+	var ce *CustomError
+	if errors.As(err, &ce) {
+		return ce.StatusCode
+	}
+	return fallback
+}
+
+// asyncErrorStatus maps an error from runAsync to an HTTP status code. A
+// full queue is server-side backpressure, not a malformed request, so it
+// is reported as 503 rather than collapsing into fallback.
+func asyncErrorStatus(err error, fallback int) int {
+	if errors.Is(err, ErrQueueFull) {
+		return http.StatusServiceUnavailable
+	}
+	return fallback
+}
+
+// ensureAsyncQueue lazily starts the async worker pool on first use, so a
+// gateway that never receives an async invocation never spins up
+// goroutines for it.
+func (g *gateway) ensureAsyncQueue() *asyncQueue {
+	g.asyncOnce.Do(func() {
+		g.async = newAsyncQueue(g, g.AsyncWorkers, g.AsyncQueueDepth, g.AsyncPerFunctionLimit)
+	})
+	return g.async
+}
+
+// runAsync enqueues fnName for asynchronous execution and returns the Job
+// tracking its progress, honouring an X-Callback-Url header on r that
+// requests the finished Job, carrying its ExecutionReport or error, be
+// POSTed back once it's ready.
+func (g *gateway) runAsync(r *http.Request, fnName string, runtime *time.Duration) (*Job, error) {
+	g.createIfUndefined(fnName)
+	return g.ensureAsyncQueue().submit(fnName, runtime, r.Header.Get(callbackHeader))
+}
+
+// job looks up a previously submitted async invocation by ID.
+func (g *gateway) job(id string) (*Job, bool) {
+	return g.ensureAsyncQueue().get(id)
+}
+
+// handleJobStatus emulates GET /v2/jobs/{id}: it reports the current
+// status of a job submitted via runAsync, or 404 if id is unknown.
+func (g *gateway) handleJobStatus(w http.ResponseWriter, r *http.Request) {
+	id := getFunctionNameFromUrl(r.URL)
+	job, ok := g.job(id)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	result, err := json.Marshal(job)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(result)
+}
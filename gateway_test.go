@@ -0,0 +1,61 @@
+package simfaas
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCustomFnContextNoDeadlineForZeroRuntime(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	ctx, cancel := customFnContext(r, 0)
+	defer cancel()
+
+	select {
+	case <-ctx.Done():
+		t.Fatal("ctx.Done() closed for a zero-duration runtime, want no deadline")
+	default:
+	}
+	if err := ctx.Err(); err != nil {
+		t.Fatalf("ctx.Err() = %v, want nil", err)
+	}
+}
+
+func TestCustomFnContextDeadlineForPositiveRuntime(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	ctx, cancel := customFnContext(r, time.Millisecond)
+	defer cancel()
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("ctx.Done() never closed for a positive-duration runtime")
+	}
+	if ctx.Err() == nil {
+		t.Fatal("ctx.Err() = nil, want a deadline-exceeded error")
+	}
+}
+
+func TestCustomErrorStatus(t *testing.T) {
+	plain := errors.New("boom")
+	ce := &CustomError{StatusCode: 418, Err: plain}
+
+	if got := customErrorStatus(ce, 400); got != 418 {
+		t.Errorf("customErrorStatus(CustomError) = %d, want 418", got)
+	}
+	if got := customErrorStatus(plain, 400); got != 400 {
+		t.Errorf("customErrorStatus(plain error) = %d, want fallback 400", got)
+	}
+}
+
+func TestAsyncErrorStatus(t *testing.T) {
+	plain := errors.New("boom")
+
+	if got := asyncErrorStatus(ErrQueueFull, 400); got != 503 {
+		t.Errorf("asyncErrorStatus(ErrQueueFull) = %d, want 503", got)
+	}
+	if got := asyncErrorStatus(plain, 400); got != 400 {
+		t.Errorf("asyncErrorStatus(plain error) = %d, want fallback 400", got)
+	}
+}
@@ -6,18 +6,89 @@ import (
 	"strings"
 )
 
-// RegexpHandler is simple http.Handler to enable the use of
-// wildcards in routes.
+// Middleware wraps an http.Handler to add cross-cutting behaviour, such
+// as logging, compression, or panic recovery, around the handlers
+// registered on a RegexpHandler.
+type Middleware func(http.Handler) http.Handler
+
+// RegexpHandler is a simple http.Handler that matches routes using
+// regular expressions and, within a match, dispatches per HTTP method the
+// way a real gateway would.
 type RegexpHandler struct {
-	routes []*route
+	routes     []*route
+	middleware []Middleware
+}
+
+// anyMethod is the key a route registered via Handler/HandleFunc is
+// stored under: it matches regardless of the incoming request's method.
+const anyMethod = ""
+
+type route struct {
+	pattern    *regexp.Regexp
+	handlers   map[string]http.Handler
+	middleware []Middleware
 }
 
+func newRoute(pattern *regexp.Regexp) *route {
+	return &route{pattern: pattern, handlers: map[string]http.Handler{}}
+}
+
+// Use registers middleware applied, in order, to every route on this
+// handler, on top of any middleware registered for the individual route.
+func (h *RegexpHandler) Use(mw ...Middleware) {
+	h.middleware = append(h.middleware, mw...)
+}
+
+// Handler registers handler to serve requests matching pattern,
+// regardless of HTTP method.
 func (h *RegexpHandler) Handler(pattern *regexp.Regexp, handler http.Handler) {
-	h.routes = append(h.routes, &route{pattern, handler})
+	r := newRoute(pattern)
+	r.handlers[anyMethod] = handler
+	h.routes = append(h.routes, r)
 }
 
+// HandleFunc is the func-based equivalent of Handler.
 func (h *RegexpHandler) HandleFunc(pattern *regexp.Regexp, handler func(http.ResponseWriter, *http.Request)) {
-	h.routes = append(h.routes, &route{pattern, http.HandlerFunc(handler)})
+	h.Handler(pattern, http.HandlerFunc(handler))
+}
+
+// Get, Post, Put, Delete and Options register handler to serve only the
+// named HTTP method for requests matching pattern. A pattern may have a
+// handler registered per method; OPTIONS is answered automatically for
+// any matched pattern unless registered explicitly here.
+func (h *RegexpHandler) Get(pattern *regexp.Regexp, handler http.Handler, mw ...Middleware) {
+	h.method(http.MethodGet, pattern, handler, mw...)
+}
+
+func (h *RegexpHandler) Post(pattern *regexp.Regexp, handler http.Handler, mw ...Middleware) {
+	h.method(http.MethodPost, pattern, handler, mw...)
+}
+
+func (h *RegexpHandler) Put(pattern *regexp.Regexp, handler http.Handler, mw ...Middleware) {
+	h.method(http.MethodPut, pattern, handler, mw...)
+}
+
+func (h *RegexpHandler) Delete(pattern *regexp.Regexp, handler http.Handler, mw ...Middleware) {
+	h.method(http.MethodDelete, pattern, handler, mw...)
+}
+
+func (h *RegexpHandler) Options(pattern *regexp.Regexp, handler http.Handler, mw ...Middleware) {
+	h.method(http.MethodOptions, pattern, handler, mw...)
+}
+
+func (h *RegexpHandler) method(method string, pattern *regexp.Regexp, handler http.Handler, mw ...Middleware) {
+	// reuse an existing route for this exact pattern so Get/Post/etc. can
+	// be layered on the same path, the way a real router would.
+	for _, r := range h.routes {
+		if r.pattern.String() == pattern.String() {
+			r.handlers[method] = handler
+			return
+		}
+	}
+	r := newRoute(pattern)
+	r.handlers[method] = handler
+	r.middleware = mw
+	h.routes = append(h.routes, r)
 }
 
 func (h *RegexpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -25,17 +96,52 @@ func (h *RegexpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	path := strings.Split(r.URL.Path, "?")[0]
 	// Reverse match, such that newer routes have precedence
 	for i := len(h.routes) - 1; i >= 0; i-- {
-		route := h.routes[i]
-		if route.pattern.MatchString(path) {
-			route.handler.ServeHTTP(w, r)
+		rt := h.routes[i]
+		if !rt.pattern.MatchString(path) {
+			continue
+		}
+
+		if handler, ok := rt.handlers[r.Method]; ok {
+			h.chain(rt, handler).ServeHTTP(w, r)
+			return
+		}
+		if handler, ok := rt.handlers[anyMethod]; ok {
+			h.chain(rt, handler).ServeHTTP(w, r)
 			return
 		}
+		if r.Method == http.MethodOptions {
+			w.Header().Set("Allow", strings.Join(rt.allowedMethods(), ", "))
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.Header().Set("Allow", strings.Join(rt.allowedMethods(), ", "))
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
 	}
 	// no pattern matched; send 404 response
 	http.NotFound(w, r)
 }
 
-type route struct {
-	pattern *regexp.Regexp
-	handler http.Handler
+// allowedMethods lists the methods registered for a route, for use in a
+// 405 response or an automatic OPTIONS reply.
+func (rt *route) allowedMethods() []string {
+	methods := make([]string, 0, len(rt.handlers)+1)
+	for m := range rt.handlers {
+		if m == anyMethod || m == http.MethodOptions {
+			continue
+		}
+		methods = append(methods, m)
+	}
+	methods = append(methods, http.MethodOptions)
+	return methods
+}
+
+func (h *RegexpHandler) chain(rt *route, handler http.Handler) http.Handler {
+	for i := len(rt.middleware) - 1; i >= 0; i-- {
+		handler = rt.middleware[i](handler)
+	}
+	for i := len(h.middleware) - 1; i >= 0; i-- {
+		handler = h.middleware[i](handler)
+	}
+	return handler
 }
@@ -0,0 +1,188 @@
+package simfaas
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestRegexpHandlerMethodDispatch(t *testing.T) {
+	var got string
+	h := &RegexpHandler{}
+	h.Get(regexp.MustCompile("/fn/.*"), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = "get"
+		w.WriteHeader(http.StatusOK)
+	}))
+	h.Post(regexp.MustCompile("/fn/.*"), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = "post"
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	cases := []struct {
+		method string
+		want   string
+	}{
+		{http.MethodGet, "get"},
+		{http.MethodPost, "post"},
+	}
+	for _, c := range cases {
+		got = ""
+		req := httptest.NewRequest(c.method, "/fn/hello", nil)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if got != c.want {
+			t.Errorf("method %s: dispatched to %q, want %q", c.method, got, c.want)
+		}
+		if rec.Code != http.StatusOK {
+			t.Errorf("method %s: status = %d, want %d", c.method, rec.Code, http.StatusOK)
+		}
+	}
+}
+
+func TestRegexpHandlerAnyMethod(t *testing.T) {
+	h := &RegexpHandler{}
+	h.HandleFunc(regexp.MustCompile("/any/.*"), func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodDelete} {
+		req := httptest.NewRequest(method, "/any/x", nil)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Errorf("method %s: status = %d, want %d", method, rec.Code, http.StatusOK)
+		}
+	}
+}
+
+func TestRegexpHandlerNotFound(t *testing.T) {
+	h := &RegexpHandler{}
+	h.Get(regexp.MustCompile("/fn/.*"), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/nope", nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestRegexpHandlerMethodNotAllowed(t *testing.T) {
+	h := &RegexpHandler{}
+	h.Get(regexp.MustCompile("/fn/.*"), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	h.Post(regexp.MustCompile("/fn/.*"), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodDelete, "/fn/hello", nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+	allow := rec.Header().Get("Allow")
+	if got := countOccurrences(allow, http.MethodOptions); got != 1 {
+		t.Errorf("Allow header %q contains OPTIONS %d times, want 1", allow, got)
+	}
+}
+
+func TestRegexpHandlerAutomaticOptions(t *testing.T) {
+	h := &RegexpHandler{}
+	h.Get(regexp.MustCompile("/fn/.*"), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodOptions, "/fn/hello", nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	allow := rec.Header().Get("Allow")
+	if got := countOccurrences(allow, http.MethodOptions); got != 1 {
+		t.Errorf("Allow header %q contains OPTIONS %d times, want 1", allow, got)
+	}
+}
+
+// TestRegexpHandlerExplicitOptionsNoDuplicate covers the case where a
+// route registers its own Options handler: the Allow header built for a
+// 405 on that same route must not list OPTIONS twice.
+func TestRegexpHandlerExplicitOptionsNoDuplicate(t *testing.T) {
+	h := &RegexpHandler{}
+	h.Get(regexp.MustCompile("/fn/.*"), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	h.Options(regexp.MustCompile("/fn/.*"), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodDelete, "/fn/hello", nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+	allow := rec.Header().Get("Allow")
+	if got := countOccurrences(allow, http.MethodOptions); got != 1 {
+		t.Errorf("Allow header %q contains OPTIONS %d times, want 1", allow, got)
+	}
+}
+
+func countOccurrences(allow, method string) int {
+	n := 0
+	for _, m := range splitAllow(allow) {
+		if m == method {
+			n++
+		}
+	}
+	return n
+}
+
+func splitAllow(allow string) []string {
+	var methods []string
+	for _, m := range regexp.MustCompile(`\s*,\s*`).Split(allow, -1) {
+		if m != "" {
+			methods = append(methods, m)
+		}
+	}
+	return methods
+}
+
+func TestRegexpHandlerMiddlewareOrder(t *testing.T) {
+	var order []string
+	mw := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	h := &RegexpHandler{}
+	h.Use(mw("global"))
+	h.Get(regexp.MustCompile("/fn/.*"), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		order = append(order, "handler")
+		w.WriteHeader(http.StatusOK)
+	}), mw("route"))
+
+	req := httptest.NewRequest(http.MethodGet, "/fn/hello", nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	want := []string{"global", "route", "handler"}
+	if len(order) != len(want) {
+		t.Fatalf("call order = %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Errorf("call order = %v, want %v", order, want)
+			break
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package simfaas
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Knative is a wrapper on top of simfaas that emulates a part of the
+// interface of Knative Serving, routing invocations by Host header
+// rather than by path. Invocations run on the shared Platform the same
+// way as through the other gateways; Knative does not force a cold start
+// per invocation and does not scale instances to zero itself.
+type Knative struct {
+	gateway
+}
+
+func (k *Knative) Serve() http.Handler {
+	m := k.metrics()
+	handler := &RegexpHandler{}
+	handler.Use(RecoverMiddleware, LoggingMiddleware, GzipMiddleware)
+	handler.Handler(regexp.MustCompile(".*"), m.Instrument("revisionRun", http.HandlerFunc(k.HandleRevisionRun)))
+	handler.Get(regexp.MustCompile("/metrics"), m.Handler())
+	return handler
+}
+
+// HandleRevisionRun emulates invoking a Knative Service/Revision. The
+// target function name is taken from the leading label of the Host
+// header ({service}.{namespace}.svc...), the way Knative's own ingress
+// routes requests.
+//
+// It checks for the presence of the runtime query parameter,
+// which allows you to override the runtime of the function.
+func (k *Knative) HandleRevisionRun(w http.ResponseWriter, r *http.Request) {
+	fnName := serviceFromHost(r.Host)
+	if fnName == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	runtime, err := runtimeOverride(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	report, custom, err := k.run(r, fnName, runtime)
+	if err != nil {
+		http.Error(w, err.Error(), customErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+	if custom != nil {
+		writeCustomResponse(w, custom)
+		return
+	}
+
+	result, err := json.Marshal(report)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(result)
+}
+
+// serviceFromHost extracts the service name from a Knative-style Host
+// header, e.g. "hello.default.svc.cluster.local" or
+// "hello.default.example.com" both yield "hello".
+func serviceFromHost(host string) string {
+	host = strings.SplitN(host, ":", 2)[0]
+	if host == "" {
+		return ""
+	}
+	return strings.SplitN(host, ".", 2)[0]
+}
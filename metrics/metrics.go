@@ -0,0 +1,238 @@
+// Package metrics exposes Prometheus collectors describing the runtime
+// behaviour of a simulated platform: invocation counts, cold starts, warm
+// hits, deploy failures, function-not-found errors, execution/cold-start/
+// queue latency, currently deployed and idle instance counts, and the
+// standard HTTP request metrics for the gateway emulations that sit in
+// front of it.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Collector is the interface the gateways report observations through,
+// from their deploy/run paths and HTTP routes. The Platform itself does
+// not report to a Collector. A nil-safe no-op implementation is available
+// via Noop, so instrumentation remains entirely optional.
+//
+// WarmHit, SetDeployedInstances and SetIdleInstances are part of the
+// interface, but no gateway calls them, so their metrics are not
+// populated.
+type Collector interface {
+	// Invocation records that fnName was invoked.
+	Invocation(fnName string)
+	// ColdStart records a cold start for fnName, and how long it took
+	// before the instance was ready to serve.
+	ColdStart(fnName string, d time.Duration)
+	// WarmHit records that an invocation was served by an already warm
+	// instance of fnName.
+	WarmHit(fnName string)
+	// DeployFailure records that deploying an instance of fnName failed.
+	DeployFailure(fnName string)
+	// FunctionNotFound records a request for an fnName that has no
+	// corresponding function defined on the platform.
+	FunctionNotFound(fnName string)
+	// ObserveRuntime records the execution runtime of an invocation.
+	ObserveRuntime(fnName string, d time.Duration)
+	// ObserveQueueWait records how long an invocation waited for an
+	// available instance before it began executing.
+	ObserveQueueWait(fnName string, d time.Duration)
+	// SetDeployedInstances reports the current number of deployed
+	// instances for fnName.
+	SetDeployedInstances(fnName string, n int)
+	// SetIdleInstances reports the current number of warm but unused
+	// instances for fnName.
+	SetIdleInstances(fnName string, n int)
+	// Instrument wraps next so every request it serves is counted and
+	// timed under the given route label.
+	Instrument(route string, next http.Handler) http.Handler
+	// Handler exposes the collected metrics for scraping, suitable for
+	// mounting at /metrics.
+	Handler() http.Handler
+}
+
+// Noop is a Collector that discards every observation and leaves HTTP
+// handlers untouched. It is the default used when a gateway is not
+// configured with a Collector.
+var Noop Collector = noopCollector{}
+
+type noopCollector struct{}
+
+func (noopCollector) Invocation(string)                      {}
+func (noopCollector) ColdStart(string, time.Duration)        {}
+func (noopCollector) WarmHit(string)                         {}
+func (noopCollector) DeployFailure(string)                   {}
+func (noopCollector) FunctionNotFound(string)                {}
+func (noopCollector) ObserveRuntime(string, time.Duration)   {}
+func (noopCollector) ObserveQueueWait(string, time.Duration) {}
+func (noopCollector) SetDeployedInstances(string, int)       {}
+func (noopCollector) SetIdleInstances(string, int)           {}
+func (noopCollector) Instrument(_ string, next http.Handler) http.Handler {
+	return next
+}
+func (noopCollector) Handler() http.Handler { return http.NotFoundHandler() }
+
+// Prometheus is the default Collector implementation. It registers its
+// collectors on a dedicated prometheus.Registry so that multiple
+// simulated platforms can run in the same process without metric name
+// collisions.
+type Prometheus struct {
+	registry *prometheus.Registry
+
+	invocations       *prometheus.CounterVec
+	coldStarts        *prometheus.CounterVec
+	warmHits          *prometheus.CounterVec
+	deployFailures    *prometheus.CounterVec
+	functionNotFound  *prometheus.CounterVec
+	runtimeSeconds    *prometheus.HistogramVec
+	coldStartSeconds  *prometheus.HistogramVec
+	queueWaitSeconds  *prometheus.HistogramVec
+	deployedInstances *prometheus.GaugeVec
+	idleInstances     *prometheus.GaugeVec
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+}
+
+// NewPrometheus builds a Prometheus-backed Collector.
+func NewPrometheus() *Prometheus {
+	p := &Prometheus{registry: prometheus.NewRegistry()}
+
+	p.invocations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "simfaas",
+		Name:      "invocations_total",
+		Help:      "Total number of function invocations.",
+	}, []string{"function"})
+	p.coldStarts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "simfaas",
+		Name:      "cold_starts_total",
+		Help:      "Total number of cold starts.",
+	}, []string{"function"})
+	p.warmHits = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "simfaas",
+		Name:      "warm_hits_total",
+		Help:      "Total number of invocations served by an already warm instance.",
+	}, []string{"function"})
+	p.deployFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "simfaas",
+		Name:      "deploy_failures_total",
+		Help:      "Total number of failed function deployments.",
+	}, []string{"function"})
+	p.functionNotFound = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "simfaas",
+		Name:      "function_not_found_total",
+		Help:      "Total number of requests for an undefined function.",
+	}, []string{"function"})
+	p.runtimeSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "simfaas",
+		Name:      "runtime_seconds",
+		Help:      "Observed execution runtime of simulated function invocations.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"function"})
+	p.coldStartSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "simfaas",
+		Name:      "cold_start_seconds",
+		Help:      "Observed cold-start latency before an invocation could run.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"function"})
+	p.queueWaitSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "simfaas",
+		Name:      "queue_wait_seconds",
+		Help:      "Observed time an invocation spent waiting for an available instance.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"function"})
+	p.deployedInstances = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "simfaas",
+		Name:      "deployed_instances",
+		Help:      "Number of instances currently deployed for a function.",
+	}, []string{"function"})
+	p.idleInstances = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "simfaas",
+		Name:      "idle_instances",
+		Help:      "Number of instances currently idle for a function.",
+	}, []string{"function"})
+	p.httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "simfaas",
+		Name:      "http_requests_total",
+		Help:      "Total number of HTTP requests served by a gateway emulation.",
+	}, []string{"route", "code"})
+	p.httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "simfaas",
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of HTTP requests served by a gateway emulation.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "code"})
+
+	p.registry.MustRegister(
+		p.invocations, p.coldStarts, p.warmHits, p.deployFailures, p.functionNotFound,
+		p.runtimeSeconds, p.coldStartSeconds, p.queueWaitSeconds,
+		p.deployedInstances, p.idleInstances,
+		p.httpRequests, p.httpDuration,
+	)
+	return p
+}
+
+func (p *Prometheus) Invocation(fnName string) { p.invocations.WithLabelValues(fnName).Inc() }
+
+func (p *Prometheus) ColdStart(fnName string, d time.Duration) {
+	p.coldStarts.WithLabelValues(fnName).Inc()
+	p.coldStartSeconds.WithLabelValues(fnName).Observe(d.Seconds())
+}
+
+func (p *Prometheus) WarmHit(fnName string) { p.warmHits.WithLabelValues(fnName).Inc() }
+
+func (p *Prometheus) DeployFailure(fnName string) { p.deployFailures.WithLabelValues(fnName).Inc() }
+
+func (p *Prometheus) FunctionNotFound(fnName string) {
+	p.functionNotFound.WithLabelValues(fnName).Inc()
+}
+
+func (p *Prometheus) ObserveRuntime(fnName string, d time.Duration) {
+	p.runtimeSeconds.WithLabelValues(fnName).Observe(d.Seconds())
+}
+
+func (p *Prometheus) ObserveQueueWait(fnName string, d time.Duration) {
+	p.queueWaitSeconds.WithLabelValues(fnName).Observe(d.Seconds())
+}
+
+func (p *Prometheus) SetDeployedInstances(fnName string, n int) {
+	p.deployedInstances.WithLabelValues(fnName).Set(float64(n))
+}
+
+func (p *Prometheus) SetIdleInstances(fnName string, n int) {
+	p.idleInstances.WithLabelValues(fnName).Set(float64(n))
+}
+
+// Handler exposes the registered collectors for scraping.
+func (p *Prometheus) Handler() http.Handler {
+	return promhttp.HandlerFor(p.registry, promhttp.HandlerOpts{})
+}
+
+// Instrument wraps next so that every request it serves increments
+// simfaas_http_requests_total{route,code} and observes
+// simfaas_http_request_duration_seconds{route,code}.
+func (p *Prometheus) Instrument(route string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		code := strconv.Itoa(rec.status)
+		p.httpRequests.WithLabelValues(route, code).Inc()
+		p.httpDuration.WithLabelValues(route, code).Observe(time.Since(start).Seconds())
+	})
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
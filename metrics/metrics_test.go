@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNoopCollectorIsSafe(t *testing.T) {
+	// Noop must tolerate every call without panicking; that's its entire
+	// contract.
+	Noop.Invocation("fn")
+	Noop.ColdStart("fn", time.Second)
+	Noop.WarmHit("fn")
+	Noop.DeployFailure("fn")
+	Noop.FunctionNotFound("fn")
+	Noop.ObserveRuntime("fn", time.Second)
+	Noop.ObserveQueueWait("fn", time.Second)
+	Noop.SetDeployedInstances("fn", 3)
+	Noop.SetIdleInstances("fn", 1)
+
+	rec := httptest.NewRecorder()
+	Noop.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Noop.Handler() status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	called := false
+	Noop.Instrument("route", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusOK)
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if !called {
+		t.Error("Noop.Instrument did not invoke the wrapped handler")
+	}
+}
+
+func TestPrometheusRecordsObservations(t *testing.T) {
+	p := NewPrometheus()
+
+	p.Invocation("fn")
+	p.ColdStart("fn", 10*time.Millisecond)
+	p.WarmHit("fn")
+	p.DeployFailure("fn")
+	p.FunctionNotFound("fn")
+	p.ObserveRuntime("fn", time.Second)
+	p.ObserveQueueWait("fn", time.Millisecond)
+	p.SetDeployedInstances("fn", 2)
+	p.SetIdleInstances("fn", 1)
+
+	rec := httptest.NewRecorder()
+	p.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Handler() status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	body := rec.Body.String()
+	for _, want := range []string{
+		"simfaas_invocations_total",
+		"simfaas_cold_starts_total",
+		"simfaas_warm_hits_total",
+		"simfaas_deploy_failures_total",
+		"simfaas_function_not_found_total",
+		"simfaas_runtime_seconds",
+		"simfaas_cold_start_seconds",
+		"simfaas_queue_wait_seconds",
+		"simfaas_deployed_instances",
+		"simfaas_idle_instances",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("scraped output missing %q", want)
+		}
+	}
+}
+
+func TestPrometheusInstrument(t *testing.T) {
+	p := NewPrometheus()
+	handler := p.Instrument("function", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusTeapot {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusTeapot)
+	}
+
+	scraped := httptest.NewRecorder()
+	p.Handler().ServeHTTP(scraped, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := scraped.Body.String()
+	if !strings.Contains(body, `simfaas_http_requests_total{code="418",route="function"}`) {
+		t.Errorf("scraped output missing instrumented request count, got: %s", body)
+	}
+}
@@ -0,0 +1,173 @@
+package simfaas
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// OpenFaaS is a wrapper on top of simfaas that emulates a part of the
+// interface of OpenFaaS, sharing the same simulated Platform backend as
+// Fission and Knative.
+type OpenFaaS struct {
+	gateway
+}
+
+// openFaasFunction mirrors the subset of OpenFaaS's function metadata
+// simfaas understands: just enough to identify the function being
+// deployed or scaled.
+type openFaasFunction struct {
+	Service  string `json:"service"`
+	Replicas int    `json:"replicas,omitempty"`
+}
+
+func (o *OpenFaaS) Serve() http.Handler {
+	m := o.metrics()
+	handler := &RegexpHandler{}
+	handler.Use(RecoverMiddleware, LoggingMiddleware, GzipMiddleware)
+	handler.Post(regexp.MustCompile("/function/.*"), m.Instrument("function", http.HandlerFunc(o.HandleFunctionRun)))
+	handler.Post(regexp.MustCompile("/async-function/.*"), m.Instrument("asyncFunction", http.HandlerFunc(o.HandleAsyncFunctionRun)))
+	handler.Post(regexp.MustCompile("/system/scale-function/.*"), m.Instrument("scaleFunction", http.HandlerFunc(o.HandleScaleFunction)))
+	handler.Handler(regexp.MustCompile("/system/functions"), m.Instrument("systemFunctions", http.HandlerFunc(o.HandleSystemFunctions)))
+	handler.Get(regexp.MustCompile("/v2/jobs/.*"), m.Instrument("jobStatus", http.HandlerFunc(o.handleJobStatus)))
+	handler.Get(regexp.MustCompile("/metrics"), m.Handler())
+	return handler
+}
+
+// HandleFunctionRun emulates the /function/{name} OpenFaaS endpoint,
+// invoking the function synchronously.
+//
+// It checks for the presence of the runtime query parameter,
+// which allows you to override the runtime of the function.
+func (o *OpenFaaS) HandleFunctionRun(w http.ResponseWriter, r *http.Request) {
+	runtime, err := runtimeOverride(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	fnName := getFunctionNameFromUrl(r.URL)
+	report, custom, err := o.run(r, fnName, runtime)
+	if err != nil {
+		http.Error(w, err.Error(), customErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+	if custom != nil {
+		writeCustomResponse(w, custom)
+		return
+	}
+
+	result, err := json.Marshal(report)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(result)
+}
+
+// HandleAsyncFunctionRun emulates the /async-function/{name} OpenFaaS
+// endpoint: it enqueues the invocation and returns 202 Accepted with a
+// Location header pointing at the job's status.
+func (o *OpenFaaS) HandleAsyncFunctionRun(w http.ResponseWriter, r *http.Request) {
+	runtime, err := runtimeOverride(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	fnName := getFunctionNameFromUrl(r.URL)
+	job, err := o.runAsync(r, fnName, runtime)
+	if err != nil {
+		http.Error(w, err.Error(), asyncErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+	w.Header().Set("Location", "/v2/jobs/"+job.ID)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// HandleScaleFunction emulates OpenFaaS's /system/scale-function/{name}
+// endpoint. Since the simulated Platform does not expose an explicit
+// replica count, scaling is approximated by tapping (pre-warming) the
+// function the requested number of times.
+func (o *OpenFaaS) HandleScaleFunction(w http.ResponseWriter, r *http.Request) {
+	bs, err := ioutil.ReadAll(r.Body)
+	defer r.Body.Close()
+	if err != nil {
+		http.Error(w, "failed to read scale request", http.StatusBadRequest)
+		return
+	}
+	var scale openFaasFunction
+	if err := json.Unmarshal(bs, &scale); err != nil {
+		http.Error(w, "failed to parse scale request", http.StatusBadRequest)
+		return
+	}
+	fnName := getFunctionNameFromUrl(r.URL)
+	if scale.Service != "" {
+		fnName = scale.Service
+	}
+	for i := 0; i < scale.Replicas; i++ {
+		if err := o.tap(fnName); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// HandleSystemFunctions emulates OpenFaaS's /system/functions CRUD
+// endpoint. GET lists deployed functions (currently always empty, as
+// simfaas does not track deployments outside of the Platform itself).
+// POST defines a function the same way an invocation with
+// CreateUndefinedFunctions would, and DELETE acknowledges removal
+// without undeploying anything from the simulated Platform.
+func (o *OpenFaaS) HandleSystemFunctions(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("[]"))
+	case http.MethodPost:
+		bs, err := ioutil.ReadAll(r.Body)
+		defer r.Body.Close()
+		if err != nil {
+			http.Error(w, "failed to read function metadata", http.StatusBadRequest)
+			return
+		}
+		var fn openFaasFunction
+		if err := json.Unmarshal(bs, &fn); err != nil || fn.Service == "" {
+			http.Error(w, "failed to parse function metadata", http.StatusBadRequest)
+			return
+		}
+		if _, ok := o.Platform.Get(fn.Service); !ok {
+			o.Platform.Define(fn.Service, o.FnFactory(fn.Service))
+			log.Printf("Created new function %s", fn.Service)
+		}
+		w.WriteHeader(http.StatusOK)
+	case http.MethodDelete:
+		w.WriteHeader(http.StatusOK)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// runtimeOverride parses the optional runtime query parameter shared by
+// every gateway's invocation routes. It always returns a non-nil
+// override, defaulting to a zero duration when the parameter is absent,
+// matching the override Fission's original HandleFunctionRun always
+// passed to Run.
+func runtimeOverride(r *http.Request) (*time.Duration, error) {
+	var seconds float64
+	if queryRuntime := r.URL.Query().Get("runtime"); len(queryRuntime) > 0 {
+		var err error
+		seconds, err = strconv.ParseFloat(queryRuntime, 64)
+		if err != nil {
+			return nil, err
+		}
+	}
+	runtime := time.Duration(seconds * float64(time.Second))
+	return &runtime, nil
+}